@@ -0,0 +1,234 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coordinator
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/pranavkevadiya/hey-with-milliseconds-output/requester"
+)
+
+type registeredWorker struct {
+	id      string
+	address string
+	client  *rpc.Client
+}
+
+// Coordinator splits one benchmark across the workers registered with
+// it and merges their streamed results into a single requester.Report,
+// so the summary/CSV/JSON/etc output is indistinguishable from a
+// single-process run.
+type Coordinator struct {
+	AuthToken string
+	TLSConfig *tls.Config
+
+	mu      sync.Mutex
+	workers map[string]*registeredWorker
+
+	// run-scoped state, set by Run and read by the RPC handlers below.
+	results  []requester.Result
+	resultCh chan struct{} // closed once every worker reports Done
+	pending  map[string]bool
+}
+
+// NewCoordinator creates a Coordinator with no workers registered yet;
+// workers call RegisterWorker once they start listening.
+func NewCoordinator(authToken string, tlsConfig *tls.Config) *Coordinator {
+	return &Coordinator{
+		AuthToken: authToken,
+		TLSConfig: tlsConfig,
+		workers:   make(map[string]*registeredWorker),
+	}
+}
+
+// ListenAndServe registers an RPC server on addr and blocks, accepting
+// RegisterWorker/ReportResults calls from workers until the process
+// exits.
+func (c *Coordinator) ListenAndServe(addr string) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Coordinator", c); err != nil {
+		return err
+	}
+
+	var l net.Listener
+	var err error
+	if c.TLSConfig != nil {
+		l, err = tls.Listen("tcp", addr, c.TLSConfig)
+	} else {
+		l, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	server.Accept(l)
+	return nil
+}
+
+// RegisterWorker is the RPC entry point a worker calls once it starts
+// listening, so it is included in the next Run's split.
+func (c *Coordinator) RegisterWorker(req *RegisterRequest, ack *Ack) error {
+	if err := checkAuth(req.AuthToken, c.AuthToken); err != nil {
+		return err
+	}
+
+	var conn net.Conn
+	var err error
+	if c.TLSConfig != nil {
+		conn, err = tls.Dial("tcp", req.Address, c.TLSConfig)
+	} else {
+		conn, err = net.Dial("tcp", req.Address)
+	}
+	if err != nil {
+		return err
+	}
+	client := rpc.NewClient(conn)
+
+	c.mu.Lock()
+	c.workers[req.WorkerID] = &registeredWorker{id: req.WorkerID, address: req.Address, client: client}
+	c.mu.Unlock()
+
+	ack.OK = true
+	return nil
+}
+
+// ReportResults is the RPC entry point workers push completed-request
+// batches to as a run progresses.
+func (c *Coordinator) ReportResults(batch *ResultBatch, ack *Ack) error {
+	if err := checkAuth(batch.AuthToken, c.AuthToken); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	for _, rec := range batch.Records {
+		var err error
+		if rec.Error != "" {
+			err = fmt.Errorf("%s", rec.Error)
+		}
+		c.results = append(c.results, requester.Result{
+			Status:        rec.Status,
+			Offset:        rec.Offset,
+			Duration:      rec.Lat,
+			ConnDuration:  rec.ConnLat,
+			DNSDuration:   rec.DnsLat,
+			ReqDuration:   rec.ReqLat,
+			DelayDuration: rec.DelayLat,
+			ResDuration:   rec.ResLat,
+			ContentLength: rec.Bytes,
+			Err:           err,
+		})
+	}
+	if batch.Done {
+		delete(c.pending, batch.WorkerID)
+		if len(c.pending) == 0 && c.resultCh != nil {
+			close(c.resultCh)
+			c.resultCh = nil
+		}
+	}
+	c.mu.Unlock()
+
+	ack.OK = true
+	return nil
+}
+
+// WaitForWorkers blocks until at least n workers have registered.
+func (c *Coordinator) WaitForWorkers(n int) {
+	for {
+		c.mu.Lock()
+		count := len(c.workers)
+		c.mu.Unlock()
+		if count >= n {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Spec describes the benchmark to split across registered workers; it
+// is the coordinator-side equivalent of requester.Work.
+type Spec struct {
+	Method           string
+	URL              string
+	Headers          map[string]string
+	Body             []byte
+	N                int
+	C                int
+	QPS              float64
+	TimeoutSeconds   int
+	DisableRedirects bool
+	Output           string
+}
+
+// Run splits spec across every registered worker, blocks until they
+// have all reported completion, and returns the merged report.
+func (c *Coordinator) Run(w io.Writer, spec Spec) (*requester.Report, error) {
+	c.mu.Lock()
+	if len(c.workers) == 0 {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("coordinator: no workers registered")
+	}
+	workers := make([]*registeredWorker, 0, len(c.workers))
+	for _, rw := range c.workers {
+		workers = append(workers, rw)
+	}
+	c.results = nil
+	c.pending = make(map[string]bool, len(workers))
+	for _, rw := range workers {
+		c.pending[rw.id] = true
+	}
+	done := make(chan struct{})
+	c.resultCh = done
+	c.mu.Unlock()
+
+	start := time.Now()
+	n := spec.N / len(workers)
+	calls := make([]*rpc.Call, len(workers))
+	for i, rw := range workers {
+		ws := &WorkSpec{
+			Method:           spec.Method,
+			URL:              spec.URL,
+			Headers:          spec.Headers,
+			Body:             spec.Body,
+			N:                n,
+			C:                spec.C,
+			QPS:              spec.QPS,
+			TimeoutSeconds:   spec.TimeoutSeconds,
+			DisableRedirects: spec.DisableRedirects,
+			StartUnixNano:    start.UnixNano(),
+			AuthToken:        c.AuthToken,
+		}
+		calls[i] = rw.client.Go("Worker.RunWork", ws, new(Ack), nil)
+	}
+	for i, call := range calls {
+		if err := (<-call.Done).Error; err != nil {
+			return nil, fmt.Errorf("coordinator: worker %s: %w", workers[i].id, err)
+		}
+	}
+
+	<-done
+
+	c.mu.Lock()
+	results := c.results
+	c.mu.Unlock()
+
+	return requester.NewMergedReport(w, spec.Output, results), nil
+}
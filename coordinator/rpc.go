@@ -0,0 +1,104 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package coordinator lets a single hey benchmark be split across
+// several machines, so the client can generate more load than a single
+// host's sockets or CPU allow. One process runs as the coordinator; one
+// or more run as workers and register with it. The coordinator splits
+// -n across the registered workers (each worker runs the full -c), and
+// each worker streams its
+// completed requests back, normalized to a shared start time, so the
+// merged output is indistinguishable from a single-process run.
+//
+// The wire schema is documented in coordinator.proto; this file
+// implements it over net/rpc/TLS rather than generated gRPC stubs,
+// since the two are equivalent for this request/response (no
+// client-streaming) shape.
+package coordinator
+
+import (
+	"crypto/subtle"
+	"fmt"
+)
+
+// WorkSpec is the benchmark configuration the coordinator hands each
+// worker. Field names mirror coordinator.proto.
+type WorkSpec struct {
+	Method  string
+	URL     string
+	Headers map[string]string
+	Body    []byte
+
+	N                int
+	C                int
+	QPS              float64
+	TimeoutSeconds   int
+	DisableRedirects bool
+
+	// StartUnixNano is a shared start time so every worker's offsets
+	// are comparable once merged back together.
+	StartUnixNano int64
+
+	AuthToken string
+}
+
+// ResultRecord mirrors one row of the CSV template's columns.
+type ResultRecord struct {
+	Lat      float64
+	ConnLat  float64
+	DnsLat   float64
+	ReqLat   float64
+	DelayLat float64
+	ResLat   float64
+	Status   int
+	Offset   float64
+	Error    string
+	Bytes    int64
+}
+
+// ResultBatch is a batch of completed requests a worker pushes back to
+// the coordinator. Batching (rather than one RPC per request) keeps the
+// RPC overhead from dominating at high QPS.
+type ResultBatch struct {
+	WorkerID string
+	Records  []ResultRecord
+	// Done marks the worker's last batch for this run, so the
+	// coordinator knows when to stop waiting on it.
+	Done bool
+
+	AuthToken string
+}
+
+// Ack is the trivial response every RPC returns so the net/rpc codec
+// always has a concrete reply type to decode into.
+type Ack struct {
+	OK bool
+}
+
+// RegisterRequest is how a worker joins the pool before a run starts.
+type RegisterRequest struct {
+	WorkerID  string
+	Address   string
+	AuthToken string
+}
+
+// checkAuth compares got against want in constant time, so a worker or
+// coordinator guessing the shared secret can't use response timing to
+// recover it byte by byte.
+func checkAuth(got, want string) error {
+	if want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		return fmt.Errorf("coordinator: invalid auth token")
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coordinator
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/pranavkevadiya/hey-with-milliseconds-output/requester"
+)
+
+// resultBatchSize is how many completed requests a worker buffers
+// before pushing a batch back to the coordinator.
+const resultBatchSize = 200
+
+// Worker runs a slice of a benchmark on behalf of a Coordinator and
+// streams its completed requests back as they finish.
+type Worker struct {
+	ID              string
+	CoordinatorAddr string
+	AuthToken       string
+	TLSConfig       *tls.Config
+
+	mu     sync.Mutex
+	client *rpc.Client
+}
+
+// ListenAndServe registers an RPC server for this worker on addr and
+// blocks, accepting RunWork calls from the coordinator until the
+// process exits.
+func (w *Worker) ListenAndServe(addr string) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Worker", w); err != nil {
+		return err
+	}
+
+	var l net.Listener
+	var err error
+	if w.TLSConfig != nil {
+		l, err = tls.Listen("tcp", addr, w.TLSConfig)
+	} else {
+		l, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	if err := w.register(addr); err != nil {
+		return fmt.Errorf("coordinator: registering with coordinator: %w", err)
+	}
+
+	server.Accept(l)
+	return nil
+}
+
+func (w *Worker) register(addr string) error {
+	c, err := w.dialCoordinator()
+	if err != nil {
+		return err
+	}
+	var ack Ack
+	return c.Call("Coordinator.RegisterWorker", &RegisterRequest{
+		WorkerID:  w.ID,
+		Address:   addr,
+		AuthToken: w.AuthToken,
+	}, &ack)
+}
+
+func (w *Worker) dialCoordinator() (*rpc.Client, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.client != nil {
+		return w.client, nil
+	}
+	var conn net.Conn
+	var err error
+	if w.TLSConfig != nil {
+		conn, err = tls.Dial("tcp", w.CoordinatorAddr, w.TLSConfig)
+	} else {
+		conn, err = net.Dial("tcp", w.CoordinatorAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.client = rpc.NewClient(conn)
+	return w.client, nil
+}
+
+// RunWork is the RPC entry point the coordinator calls to start this
+// worker's slice of a benchmark.
+func (w *Worker) RunWork(spec *WorkSpec, ack *Ack) error {
+	if err := checkAuth(spec.AuthToken, w.AuthToken); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(spec.Method, spec.URL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range spec.Headers {
+		req.Header.Set(k, v)
+	}
+
+	batch := make([]ResultRecord, 0, resultBatchSize)
+	flush := func(done bool) {
+		if len(batch) == 0 && !done {
+			return
+		}
+		c, err := w.dialCoordinator()
+		if err != nil {
+			return
+		}
+		var reply Ack
+		c.Call("Coordinator.ReportResults", &ResultBatch{
+			WorkerID:  w.ID,
+			Records:   batch,
+			Done:      done,
+			AuthToken: w.AuthToken,
+		}, &reply)
+		batch = batch[:0]
+	}
+
+	work := &requester.Work{
+		Request:          req,
+		RequestBody:      spec.Body,
+		N:                int(spec.N),
+		C:                int(spec.C),
+		QPS:              spec.QPS,
+		Timeout:          int(spec.TimeoutSeconds),
+		DisableRedirects: spec.DisableRedirects,
+		Transport:        http.DefaultTransport,
+		Writer:           ioutil.Discard,
+		StartAt:          time.Unix(0, spec.StartUnixNano),
+		OnResult: func(res requester.Result) {
+			rec := ResultRecord{
+				Lat:      res.Duration,
+				ConnLat:  res.ConnDuration,
+				DnsLat:   res.DNSDuration,
+				ReqLat:   res.ReqDuration,
+				DelayLat: res.DelayDuration,
+				ResLat:   res.ResDuration,
+				Status:   res.Status,
+				Offset:   res.Offset,
+				Bytes:    res.ContentLength,
+			}
+			if res.Err != nil {
+				rec.Error = res.Err.Error()
+			}
+			batch = append(batch, rec)
+			if len(batch) >= resultBatchSize {
+				flush(false)
+			}
+		},
+	}
+	work.Run()
+	flush(true)
+
+	ack.OK = true
+	return nil
+}
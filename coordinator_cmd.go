@@ -0,0 +1,146 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pranavkevadiya/hey-with-milliseconds-output/coordinator"
+)
+
+// runCoordinator implements `hey coordinator`: it accepts the usual
+// load-generation flags plus the target URL, waits for workers to
+// register, splits the run across them, and prints the merged report.
+func runCoordinator(args []string) {
+	fs := flag.NewFlagSet("coordinator", flag.ExitOnError)
+	addr := fs.String("addr", ":9991", "Address to listen on for worker registration and results.")
+	authToken := fs.String("auth-token", "", "Shared secret workers must present when registering or reporting.")
+	waitFor := fs.Int("wait-for-workers", 1, "Number of workers to wait for before starting the run.")
+
+	tlsCert := fs.String("tls-cert", "", "TLS certificate to present to workers, and when dialing back to them. Requires -tls-key and -tls-ca.")
+	tlsKey := fs.String("tls-key", "", "TLS private key matching -tls-cert.")
+	tlsCA := fs.String("tls-ca", "", "CA certificate used to verify worker certificates.")
+
+	n := fs.Int("n", 200, "Number of requests to run, split across all registered workers.")
+	c := fs.Int("c", 50, "Number of workers (virtual users) to run concurrently, per node.")
+	q := fs.Float64("q", 0, "Rate limit, in queries per second (QPS), per node.")
+	o := fs.String("o", "", "Output type: \"\", \"csv\", \"json\", \"prom\".")
+	m := fs.String("m", "GET", "HTTP method.")
+	body := fs.String("d", "", "HTTP request body.")
+	bodyFile := fs.String("D", "", "HTTP request body from file.")
+	timeout := fs.Int("t", 20, "Timeout for each request in seconds.")
+	disableRedirects := fs.Bool("disable-redirects", false, "Disable following of HTTP redirects.")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "usage: hey coordinator [flags] <url>")
+		os.Exit(1)
+	}
+	target := fs.Arg(0)
+	if _, err := url.Parse(target); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	bodyAll := []byte(*body)
+	if *bodyFile != "" {
+		b, err := ioutil.ReadFile(*bodyFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		bodyAll = b
+	}
+
+	tlsConfig, err := coordinator.LoadTLSConfig(*tlsCert, *tlsKey, *tlsCA)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	co := coordinator.NewCoordinator(*authToken, tlsConfig)
+	go func() {
+		if err := co.ListenAndServe(*addr); err != nil {
+			fmt.Fprintln(os.Stderr, "coordinator:", err)
+			os.Exit(1)
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "waiting for %d worker(s) to register on %s...\n", *waitFor, *addr)
+	co.WaitForWorkers(*waitFor)
+
+	report, err := co.Run(os.Stdout, coordinator.Spec{
+		Method:           strings.ToUpper(*m),
+		URL:              target,
+		Body:             bodyAll,
+		N:                *n,
+		C:                *c,
+		QPS:              *q,
+		TimeoutSeconds:   *timeout,
+		DisableRedirects: *disableRedirects,
+		Output:           *o,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "coordinator:", err)
+		os.Exit(1)
+	}
+	_ = report // already written to os.Stdout by Run via the template pipeline
+}
+
+// runWorker implements `hey worker`: it registers with a coordinator
+// and waits to be handed a slice of a benchmark to run.
+func runWorker(args []string) {
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	id := fs.String("id", "", "Unique id for this worker. Defaults to its listen address.")
+	addr := fs.String("addr", ":9992", "Address to listen on for the coordinator's RunWork call.")
+	coordinatorAddr := fs.String("coordinator", "", "Address of the coordinator to register with.")
+	authToken := fs.String("auth-token", "", "Shared secret to present to the coordinator.")
+	tlsCert := fs.String("tls-cert", "", "TLS certificate to present to the coordinator, and when listening for its RunWork call. Requires -tls-key and -tls-ca.")
+	tlsKey := fs.String("tls-key", "", "TLS private key matching -tls-cert.")
+	tlsCA := fs.String("tls-ca", "", "CA certificate used to verify the coordinator's certificate.")
+	fs.Parse(args)
+
+	if *coordinatorAddr == "" {
+		fmt.Fprintln(os.Stderr, "worker: -coordinator is required")
+		os.Exit(1)
+	}
+	workerID := *id
+	if workerID == "" {
+		workerID = *addr
+	}
+
+	tlsConfig, err := coordinator.LoadTLSConfig(*tlsCert, *tlsKey, *tlsCA)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	w := &coordinator.Worker{
+		ID:              workerID,
+		CoordinatorAddr: *coordinatorAddr,
+		AuthToken:       *authToken,
+		TLSConfig:       tlsConfig,
+	}
+	fmt.Fprintf(os.Stderr, "worker %s listening on %s, coordinator %s\n", workerID, *addr, *coordinatorAddr)
+	if err := w.ListenAndServe(*addr); err != nil {
+		fmt.Fprintln(os.Stderr, "worker:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pranavkevadiya/hey-with-milliseconds-output/requester"
+)
+
+var (
+	flagN = flag.Int("n", 200, "Number of requests to run.")
+	flagC = flag.Int("c", 50, "Number of workers to run concurrently.")
+	flagQ = flag.Float64("q", 0, "Rate limit, in queries per second (QPS). Default is no rate limit.")
+	flagO = flag.String("o", "", `Output type. If none provided, a summary is printed.
+	"csv" is an alternative that dumps the response metrics in comma-separated values format.
+	"json" dumps the summary as a single JSON document. "ndjson" streams one JSON object per
+	completed request as the run progresses. "prom" emits Prometheus text exposition format.`)
+
+	flagOFile = flag.String("o-file", "", "Write output to this file instead of stdout. Most useful with -o ndjson.")
+
+	flagM    = flag.String("m", "GET", "HTTP method.")
+	flagBody = flag.String("d", "", "HTTP request body.")
+	flagFile = flag.String("D", "", "HTTP request body from file. For example, -D body.json.")
+
+	flagTimeout = flag.Int("t", 20, "Timeout for each request in seconds.")
+
+	flagDisableRedirects = flag.Bool("disable-redirects", false, "Disable following of HTTP redirects.")
+
+	flagLive = flag.Bool("live", false, `Render a live-updating terminal view (RPS, error rate,
+	status codes, histogram, rolling quantiles) instead of only printing a summary at the end.`)
+
+	flagPushGateway    = flag.String("push-gateway", "", "Push the run's metrics to a Prometheus Pushgateway at this URL when done.")
+	flagPushGatewayJob = flag.String("push-gateway-job", "hey", "Job name to push metrics under.")
+
+	flagPlotDir = flag.String("plot", "", "Write latency histogram, percentile, RPS-over-time and status-code charts (PNG) to this directory when done.")
+	flagPlotSVG = flag.Bool("plot-svg", false, "Also write an SVG alongside each PNG in -plot.")
+
+	flagScenario = flag.String("scenario", "", `Path to a scenario YAML file declaring multiple named, weighted or
+	sequenced requests with templated URL/headers/body, instead of hammering a single URL. When set, the
+	positional <url> argument is not required and -m/-d/-D are ignored.`)
+
+	flagBucket = flag.String("bucket", "", `Response time histogram bucketing, live or final. Default is 10
+	linear buckets spanning the run's fastest..slowest. Accepts "linear:min:width:count",
+	"exponential:min:factor:count", or a comma-separated list of ascending upper bounds
+	("0.1,0.25,0.5,1,2,5").`)
+)
+
+func main() {
+	switch {
+	case len(os.Args) > 1 && os.Args[1] == "coordinator":
+		runCoordinator(os.Args[2:])
+		return
+	case len(os.Args) > 1 && os.Args[1] == "worker":
+		runWorker(os.Args[2:])
+		return
+	}
+
+	flag.Parse()
+
+	bucketSpec, err := requester.ParseBucketSpec(*flagBucket)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+
+	var outW io.Writer = os.Stdout
+	if *flagOFile != "" {
+		f, err := os.Create(*flagOFile)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		defer f.Close()
+		outW = f
+	}
+
+	if *flagScenario != "" {
+		scenario, err := requester.LoadScenario(*flagScenario)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		sw := &requester.ScenarioWork{
+			Scenario:  scenario,
+			N:         *flagN,
+			VUs:       *flagC,
+			Timeout:   *flagTimeout,
+			Transport: http.DefaultTransport,
+			Writer:    outW,
+			Output:    *flagO,
+		}
+		sw.Run()
+		return
+	}
+
+	if flag.NArg() < 1 {
+		usageAndExit("")
+	}
+
+	target := flag.Args()[0]
+	u, err := url.Parse(target)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+
+	bodyAll := []byte(*flagBody)
+	if *flagFile != "" {
+		b, err := ioutil.ReadFile(*flagFile)
+		if err != nil {
+			usageAndExit(err.Error())
+		}
+		bodyAll = b
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(*flagM), u.String(), nil)
+	if err != nil {
+		usageAndExit(err.Error())
+	}
+
+	w := &requester.Work{
+		Request:          req,
+		RequestBody:      bodyAll,
+		N:                *flagN,
+		C:                *flagC,
+		QPS:              *flagQ,
+		Output:           *flagO,
+		Writer:           outW,
+		Timeout:          *flagTimeout,
+		DisableRedirects: *flagDisableRedirects,
+		Transport:        http.DefaultTransport,
+		Live:             *flagLive,
+		PushGatewayURL:   *flagPushGateway,
+		PushGatewayJob:   *flagPushGatewayJob,
+		PlotDir:          *flagPlotDir,
+		PlotSVG:          *flagPlotSVG,
+		HistogramSpec:    bucketSpec,
+	}
+	w.Run()
+}
+
+func usageAndExit(msg string) {
+	if msg != "" {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	flag.Usage()
+	os.Exit(1)
+}
@@ -0,0 +1,102 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// traceTimes holds the httptrace stage timestamps for one request. The
+// callbacks that set them run on whatever goroutine the transport
+// chooses (e.g. a background dial), which isn't guaranteed to be the
+// one that called client.Do, so every read and write goes through mu
+// rather than relying on client.Do's return as a happens-before edge.
+type traceTimes struct {
+	mu                                                  sync.Mutex
+	dnsStart, connStart, reqStart, delayStart, resStart time.Time
+}
+
+func (t *traceTimes) setDNSStart()   { t.mu.Lock(); t.dnsStart = time.Now(); t.mu.Unlock() }
+func (t *traceTimes) setConnStart()  { t.mu.Lock(); t.connStart = time.Now(); t.mu.Unlock() }
+func (t *traceTimes) setReqStart()   { t.mu.Lock(); t.reqStart = time.Now(); t.mu.Unlock() }
+func (t *traceTimes) setDelayStart() { t.mu.Lock(); t.delayStart = time.Now(); t.mu.Unlock() }
+func (t *traceTimes) setResStart()   { t.mu.Lock(); t.resStart = time.Now(); t.mu.Unlock() }
+
+func (t *traceTimes) snapshot() (dnsStart, connStart, reqStart, delayStart, resStart time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dnsStart, t.connStart, t.reqStart, t.delayStart, t.resStart
+}
+
+// execRequest issues req with client, timing each stage with
+// httptrace the same way Work.makeRequest does, and returns the result
+// as the package's public Result type. When readBody is true the
+// response body is buffered and returned instead of discarded, for
+// callers (scenario steps) that need to capture values out of it.
+//
+// Offset is left zero; callers that track elapsed-since-start (Work)
+// fill it in themselves.
+func execRequest(client *http.Client, req *http.Request, readBody bool) (Result, []byte) {
+	s := time.Now()
+	var size int64
+	var code int
+	tt := &traceTimes{}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { tt.setDNSStart() },
+		ConnectStart:         func(string, string) { tt.setConnStart() },
+		GotConn:              func(httptrace.GotConnInfo) { tt.setReqStart() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { tt.setDelayStart() },
+		GotFirstResponseByte: func() { tt.setResStart() },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	resp, err := client.Do(req)
+	var body []byte
+	var dnsDuration, connDuration, reqDuration, delayDuration, resDuration time.Duration
+	if err == nil {
+		size = resp.ContentLength
+		code = resp.StatusCode
+		if readBody {
+			body, _ = ioutil.ReadAll(resp.Body)
+		} else {
+			io.Copy(ioutil.Discard, resp.Body)
+		}
+		resp.Body.Close()
+		dnsStart, connStart, reqStart, delayStart, resStart := tt.snapshot()
+		resDuration = time.Now().Sub(resStart)
+		delayDuration = resStart.Sub(delayStart)
+		reqDuration = delayStart.Sub(reqStart)
+		connDuration = reqStart.Sub(connStart)
+		dnsDuration = connStart.Sub(dnsStart)
+	}
+
+	return Result{
+		Status:        code,
+		Duration:      time.Now().Sub(s).Seconds(),
+		ConnDuration:  connDuration.Seconds(),
+		DNSDuration:   dnsDuration.Seconds(),
+		ReqDuration:   reqDuration.Seconds(),
+		ResDuration:   resDuration.Seconds(),
+		DelayDuration: delayDuration.Seconds(),
+		ContentLength: size,
+		Err:           err,
+	}, body
+}
@@ -0,0 +1,91 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonpathExtract pulls a single string value out of a JSON document
+// using a small subset of JSONPath: dot-separated field names with
+// optional "[n]" array indices, e.g. "$.data.items[0].id" or
+// "token" (the leading "$." is optional). It covers what a scenario
+// capture needs -- pulling one field out of a login/create response --
+// not the full JSONPath grammar.
+func jsonpathExtract(body []byte, path string) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "", fmt.Errorf("requester: capture: invalid JSON response: %w", err)
+	}
+
+	path = strings.TrimPrefix(path, "$.")
+	path = strings.TrimPrefix(path, "$")
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			continue
+		}
+		name, indices := splitIndices(part)
+		if name != "" {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("requester: capture: %q is not an object", name)
+			}
+			v, ok = m[name]
+			if !ok {
+				return "", fmt.Errorf("requester: capture: field %q not found", name)
+			}
+		}
+		for _, idx := range indices {
+			arr, ok := v.([]interface{})
+			if !ok || idx >= len(arr) {
+				return "", fmt.Errorf("requester: capture: index %d out of range", idx)
+			}
+			v = arr[idx]
+		}
+	}
+
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case nil:
+		return "", nil
+	default:
+		b, err := json.Marshal(t)
+		return string(b), err
+	}
+}
+
+// splitIndices splits "items[0][1]" into ("items", [0, 1]).
+func splitIndices(part string) (string, []int) {
+	i := strings.IndexByte(part, '[')
+	if i < 0 {
+		return part, nil
+	}
+	name := part[:i]
+	var indices []int
+	for _, seg := range strings.Split(part[i:], "[") {
+		seg = strings.TrimSuffix(seg, "]")
+		if seg == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(seg); err == nil {
+			indices = append(indices, n)
+		}
+	}
+	return name, indices
+}
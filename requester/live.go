@@ -0,0 +1,92 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// defaultLiveInterval is how often the --live view redraws when Work.Live
+// is set but Work.LiveInterval is zero.
+const defaultLiveInterval = 200 * time.Millisecond
+
+// liveRenderer repaints a terminal view of a Report's StreamStats on a
+// fixed interval, similar in spirit to plow's live output.
+type liveRenderer struct {
+	w        io.Writer
+	report   *Report
+	start    time.Time
+	interval time.Duration
+	done     chan struct{}
+}
+
+func newLiveRenderer(w io.Writer, r *Report, interval time.Duration, start time.Time) *liveRenderer {
+	if interval <= 0 {
+		interval = defaultLiveInterval
+	}
+	return &liveRenderer{w: w, report: r, start: start, interval: interval, done: make(chan struct{})}
+}
+
+// run repaints the view until Stop is called. It is meant to be run in
+// its own goroutine alongside Report.collect.
+func (l *liveRenderer) run() {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.draw()
+		case <-l.done:
+			l.draw()
+			return
+		}
+	}
+}
+
+func (l *liveRenderer) Stop() { close(l.done) }
+
+func (l *liveRenderer) draw() {
+	snap := l.report.stream.Snapshot(time.Since(l.start).Seconds())
+	errRate := 0.0
+	if total := snap.Count + snap.ErrorCount; total > 0 {
+		errRate = 100 * float64(snap.ErrorCount) / float64(total)
+	}
+
+	// Clear the screen and move the cursor home so the view redraws in
+	// place instead of scrolling.
+	fmt.Fprint(l.w, "\x1b[H\x1b[2J")
+	fmt.Fprintf(l.w, "hey --live  (%s elapsed)\n\n", time.Since(l.start).Round(time.Millisecond))
+	fmt.Fprintf(l.w, "Requests:\t%d\n", snap.Count)
+	fmt.Fprintf(l.w, "RPS:\t\t%.2f\n", snap.Rps)
+	fmt.Fprintf(l.w, "Error rate:\t%.2f%%\n", errRate)
+	fmt.Fprintf(l.w, "Average:\t%s\n\n", formatNumberToMillis(snap.Average)+" ms")
+
+	fmt.Fprintf(l.w, "Quantiles:\tp50 %s  p90 %s  p95 %s  p99 %s\n\n",
+		ms(snap.P50), ms(snap.P90), ms(snap.P95), ms(snap.P99))
+
+	fmt.Fprint(l.w, "Histogram:\n")
+	fmt.Fprint(l.w, histogram(snap.Histogram))
+
+	fmt.Fprint(l.w, "\nStatus codes:\n")
+	for code, n := range snap.StatusCodeDist {
+		fmt.Fprintf(l.w, "  [%d]\t%d\n", code, n)
+	}
+}
+
+func ms(seconds float64) string {
+	return fmt.Sprintf("%.0fms", seconds*1000)
+}
@@ -0,0 +1,65 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"io"
+	"time"
+)
+
+// NewMergedReport builds a Report from Results collected out-of-process
+// (by the coordinator package, from its workers), feeding them through
+// the same finalize/template pipeline a single-process Work.Run uses.
+// Offsets in results must already be normalized to one shared start
+// time, so the merged histogram/quantiles/CSV rows are indistinguishable
+// from a run that never left one machine.
+func NewMergedReport(w io.Writer, output string, results []Result) *Report {
+	r := newReport(w, nil, output, len(results), BucketSpec{}, nil)
+	accumulate(r, results)
+	r.finalize()
+	return r
+}
+
+func accumulate(r *Report, results []Result) {
+	for i := range results {
+		res := &result{
+			statusCode:    results[i].Status,
+			offset:        time.Duration(results[i].Offset * float64(time.Second)),
+			duration:      time.Duration(results[i].Duration * float64(time.Second)),
+			connDuration:  time.Duration(results[i].ConnDuration * float64(time.Second)),
+			dnsDuration:   time.Duration(results[i].DNSDuration * float64(time.Second)),
+			reqDuration:   time.Duration(results[i].ReqDuration * float64(time.Second)),
+			resDuration:   time.Duration(results[i].ResDuration * float64(time.Second)),
+			delayDuration: time.Duration(results[i].DelayDuration * float64(time.Second)),
+			contentLength: results[i].ContentLength,
+			err:           results[i].Err,
+		}
+		r.stream.Observe(res)
+		if res.err != nil {
+			r.ErrorDist[res.err.Error()]++
+			continue
+		}
+		r.Lats = append(r.Lats, res.duration.Seconds())
+		r.ConnLats = append(r.ConnLats, res.connDuration.Seconds())
+		r.DnsLats = append(r.DnsLats, res.dnsDuration.Seconds())
+		r.ReqLats = append(r.ReqLats, res.reqDuration.Seconds())
+		r.DelayLats = append(r.DelayLats, res.delayDuration.Seconds())
+		r.ResLats = append(r.ResLats, res.resDuration.Seconds())
+		r.Offsets = append(r.Offsets, res.offset.Seconds())
+		r.StatusCodes = append(r.StatusCodes, res.statusCode)
+		r.StatusCodeDist[res.statusCode]++
+		r.SizeTotal += res.contentLength
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+// ndjsonRecord is one line of `-o ndjson` output: a single completed
+// request, emitted as soon as it finishes so downstream tools (jq,
+// ClickHouse, DuckDB) can consume results while the run is still going.
+type ndjsonRecord struct {
+	ResponseTime float64 `json:"response-time"`
+	DNS          float64 `json:"dns"`
+	Connect      float64 `json:"connect"`
+	ReqWrite     float64 `json:"req-write"`
+	RespDelay    float64 `json:"resp-delay"`
+	RespRead     float64 `json:"resp-read"`
+	Status       int     `json:"status"`
+	Offset       float64 `json:"offset"`
+	Error        string  `json:"error,omitempty"`
+	Bytes        int64   `json:"bytes"`
+}
+
+func newNDJSONRecord(res *result) ndjsonRecord {
+	rec := ndjsonRecord{
+		ResponseTime: res.duration.Seconds(),
+		DNS:          res.dnsDuration.Seconds(),
+		Connect:      res.connDuration.Seconds(),
+		ReqWrite:     res.reqDuration.Seconds(),
+		RespDelay:    res.delayDuration.Seconds(),
+		RespRead:     res.resDuration.Seconds(),
+		Status:       res.statusCode,
+		Offset:       res.offset.Seconds(),
+		Bytes:        res.contentLength,
+	}
+	if res.err != nil {
+		rec.Error = res.err.Error()
+	}
+	return rec
+}
@@ -0,0 +1,143 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// SavePlots renders r's latency histogram, percentile curve, RPS-over-time
+// and per-status-code distribution to dir as PNGs (and, if svg is true,
+// SVGs too), using gonum/plot so hey needs no external plotting binary.
+func SavePlots(dir string, r *Report, svg bool) error {
+	charts := map[string]func() (*plot.Plot, error){
+		"latency-histogram.png":  r.latencyHistogramPlot,
+		"latency-percentile.png": r.latencyPercentilePlot,
+		"rps-over-time.png":      r.rpsOverTimePlot,
+		"status-codes.png":       r.statusCodesPlot,
+	}
+
+	for name, build := range charts {
+		p, err := build()
+		if err != nil {
+			return fmt.Errorf("requester: plotting %s: %w", name, err)
+		}
+		if err := p.Save(8*vg.Inch, 5*vg.Inch, filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("requester: saving %s: %w", name, err)
+		}
+		if svg {
+			svgName := name[:len(name)-len(".png")] + ".svg"
+			if err := p.Save(8*vg.Inch, 5*vg.Inch, filepath.Join(dir, svgName)); err != nil {
+				return fmt.Errorf("requester: saving %s: %w", svgName, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Report) latencyHistogramPlot() (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = "Response time histogram"
+	p.X.Label.Text = "latency (s)"
+	p.Y.Label.Text = "count"
+
+	bars, err := plotter.NewBarChart(bucketsToValues(r.Histogram), vg.Points(20))
+	if err != nil {
+		return nil, err
+	}
+	p.Add(bars)
+	return p, nil
+}
+
+func (r *Report) latencyPercentilePlot() (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = "Latency percentile distribution"
+	p.X.Label.Text = "percentile"
+	p.Y.Label.Text = "latency (s)"
+
+	pts := make(plotter.XYs, len(r.LatencyDistribution))
+	for i, d := range r.LatencyDistribution {
+		pts[i].X = float64(d.Percentage)
+		pts[i].Y = d.Latency
+	}
+	line, err := plotter.NewLine(pts)
+	if err != nil {
+		return nil, err
+	}
+	p.Add(line)
+	return p, nil
+}
+
+func (r *Report) rpsOverTimePlot() (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = "Requests per second over time"
+	p.X.Label.Text = "time (s)"
+	p.Y.Label.Text = "rps"
+
+	pts := make(plotter.XYs, len(r.RPSSeries))
+	for i, v := range r.RPSSeries {
+		pts[i].X = float64(i) * r.RPSBucketWidth
+		pts[i].Y = v
+	}
+	line, err := plotter.NewLine(pts)
+	if err != nil {
+		return nil, err
+	}
+	p.Add(line)
+	return p, nil
+}
+
+func (r *Report) statusCodesPlot() (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = "Status codes over time"
+	p.X.Label.Text = "time (s)"
+	p.Y.Label.Text = "count"
+
+	codes := make([]int, 0, len(r.StatusCodeSeries))
+	for code := range r.StatusCodeSeries {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	for _, code := range codes {
+		series := r.StatusCodeSeries[code]
+		pts := make(plotter.XYs, len(series))
+		for i, v := range series {
+			pts[i].X = float64(i) * r.RPSBucketWidth
+			pts[i].Y = v
+		}
+		line, err := plotter.NewLine(pts)
+		if err != nil {
+			return nil, err
+		}
+		p.Add(line)
+		p.Legend.Add(fmt.Sprintf("%d", code), line)
+	}
+	return p, nil
+}
+
+func bucketsToValues(buckets []Bucket) plotter.Values {
+	vs := make(plotter.Values, len(buckets))
+	for i, b := range buckets {
+		vs[i] = float64(b.Count)
+	}
+	return vs
+}
@@ -42,6 +42,9 @@ import (
 	"text/template"
 )
 
+// barChar is the character used to draw histogram bars.
+const barChar = "∎"
+
 func newTemplate(output string) *template.Template {
 	outputTmpl := output
 	switch outputTmpl {
@@ -49,6 +52,10 @@ func newTemplate(output string) *template.Template {
 		outputTmpl = defaultTmpl
 	case "csv":
 		outputTmpl = csvTmpl
+	case "prom":
+		outputTmpl = promTmpl
+	case "json":
+		outputTmpl = jsonTmpl
 	}
 	return template.Must(template.New("tmpl").Funcs(tmplFuncMap).Parse(outputTmpl))
 }
@@ -60,6 +67,11 @@ var tmplFuncMap = template.FuncMap{
 	"formatNumberIntToMillis": formatNumberIntToMillis,
 	"histogram":               histogram,
 	"jsonify":                 jsonify,
+	"promHistogram":           promHistogram,
+	"promQuantiles":           promQuantiles,
+	"promStatusCodes":         promStatusCodes,
+	"promErrors":              promErrors,
+	"formatSum":               formatSum,
 }
 
 func jsonify(v interface{}) string {
@@ -132,7 +144,36 @@ Status code distribution:{{ range $code, $num := .StatusCodeDist }}
 
 {{ if gt (len .ErrorDist) 0 }}Error distribution:{{ range $err, $num := .ErrorDist }}
   [{{ $num }}]	{{ $err }}{{ end }}{{ end }}
+{{- if gt (len .ByStep) 0 }}
+By step:{{ range .ByStep }}
+  {{ .Name }}:	{{ .Count }} requests, avg {{ formatNumberToMillis .Average }} millis{{ range $code, $num := .StatusCodeDist }}
+    [{{ $code }}]	{{ $num }} responses{{ end }}{{ end }}{{ end }}
 `
 	csvTmpl = `{{ $connLats := .ConnLats }}{{ $dnsLats := .DnsLats }}{{ $dnsLats := .DnsLats }}{{ $reqLats := .ReqLats }}{{ $delayLats := .DelayLats }}{{ $resLats := .ResLats }}{{ $statusCodeLats := .StatusCodes }}{{ $offsets := .Offsets}}response-time,DNS+dialup,DNS,Request-write,Response-delay,Response-read,status-code,offset{{ range $i, $v := .Lats }}
 {{ formatNumberToMillis $v }},{{ formatNumberToMillis (index $connLats $i) }},{{ formatNumberToMillis (index $dnsLats $i) }},{{ formatNumberToMillis (index $reqLats $i) }},{{ formatNumberToMillis (index $delayLats $i) }},{{ formatNumberToMillis (index $resLats $i) }},{{ formatNumberInt (index $statusCodeLats $i) }},{{ formatNumberToMillis (index $offsets $i) }}{{ end }}`
+
+	// promTmpl renders the run's summary in Prometheus text exposition
+	// format so it can be scraped, pushed to a Pushgateway, or archived
+	// alongside application metrics from the same benchmark.
+	promTmpl = `# HELP hey_requests_total Total number of requests by status code.
+# TYPE hey_requests_total counter
+{{ promStatusCodes .StatusCodeDist }}
+# HELP hey_request_errors_total Total number of failed requests by error.
+# TYPE hey_request_errors_total counter
+{{ promErrors .ErrorDist }}
+# HELP hey_request_duration_seconds Histogram of request durations.
+# TYPE hey_request_duration_seconds histogram
+{{ promHistogram .Histogram (len .Lats) (formatSum .Lats) }}
+# HELP hey_request_duration_seconds_quantile Quantiles of request duration over the whole run, in seconds.
+# TYPE hey_request_duration_seconds_quantile gauge
+{{ promQuantiles .LatencyDistribution }}
+# HELP hey_requests_per_second Requests completed per second over the whole run.
+# TYPE hey_requests_per_second gauge
+hey_requests_per_second {{ formatNumber .Rps }}
+`
+
+	// jsonTmpl renders the same summary fields as defaultTmpl, but as a
+	// single JSON document, for tools that want structured output
+	// instead of scraping the human-readable summary.
+	jsonTmpl = `{{ jsonify . }}`
 )
@@ -0,0 +1,109 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+func formatSum(lats []float64) string {
+	return fmt.Sprintf("%v", sum(lats))
+}
+
+// promHistogram renders buckets as a Prometheus histogram, which expects
+// cumulative counts keyed by an inclusive upper bound ("le"), plus a
+// trailing +Inf bucket, _sum and _count.
+func promHistogram(buckets []Bucket, count int, sum string) string {
+	res := new(bytes.Buffer)
+	cumulative := 0
+	for _, b := range buckets {
+		cumulative += b.Count
+		fmt.Fprintf(res, "hey_request_duration_seconds_bucket{le=\"%v\"} %d\n", b.UpperBound, cumulative)
+	}
+	fmt.Fprintf(res, "hey_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", count)
+	fmt.Fprintf(res, "hey_request_duration_seconds_sum %s\n", sum)
+	fmt.Fprintf(res, "hey_request_duration_seconds_count %d", count)
+	return res.String()
+}
+
+// promQuantiles renders the latency distribution as gauges, one per
+// quantile, matching how prometheus/client_golang exposes a Summary.
+func promQuantiles(dist []LatencyDistribution) string {
+	res := new(bytes.Buffer)
+	for i, d := range dist {
+		if i > 0 {
+			res.WriteString("\n")
+		}
+		fmt.Fprintf(res, "hey_request_duration_seconds_quantile{quantile=\"0.%02d\"} %v", d.Percentage, d.Latency)
+	}
+	return res.String()
+}
+
+func promStatusCodes(dist map[int]int) string {
+	res := new(bytes.Buffer)
+	first := true
+	for code, n := range dist {
+		if !first {
+			res.WriteString("\n")
+		}
+		first = false
+		fmt.Fprintf(res, "hey_requests_total{status=\"%d\"} %d", code, n)
+	}
+	return res.String()
+}
+
+func promErrors(dist map[string]int) string {
+	res := new(bytes.Buffer)
+	first := true
+	for err, n := range dist {
+		if !first {
+			res.WriteString("\n")
+		}
+		first = false
+		fmt.Fprintf(res, "hey_request_errors_total{error=%q} %d", err, n)
+	}
+	return res.String()
+}
+
+// PushToGateway renders r in Prometheus format and pushes it to a
+// Prometheus Pushgateway at gatewayURL under the given job name, so a
+// one-shot hey run can be archived alongside long-lived application
+// metrics instead of only printed to stdout.
+func PushToGateway(gatewayURL, job string, r *Report) error {
+	buf := new(bytes.Buffer)
+	tmpl := newTemplate("prom")
+	if err := tmpl.Execute(buf, r); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/metrics/job/%s", gatewayURL, job)
+	req, err := http.NewRequest(http.MethodPut, url, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("requester: pushgateway returned status %s", resp.Status)
+	}
+	return nil
+}
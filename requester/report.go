@@ -0,0 +1,362 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Bucket is one bar of the response time histogram. Mark is this
+// bucket's label value, printed by the text histogram in print.go: for
+// the default adaptive histogram it's the bucket's lower bound
+// (matching classic hey's bar-chart labeling); for an explicit,
+// linear or exponential BucketSpec it's the bucket's upper bound,
+// since those are defined by ascending bounds. UpperBound is always
+// this bucket's inclusive upper edge regardless of which of the two
+// conventions Mark follows, for consumers (like the Prometheus "le"
+// label) that need an unambiguous cumulative boundary.
+type Bucket struct {
+	Mark       float64
+	UpperBound float64
+	Count      int
+}
+
+// LatencyDistribution holds the latency at a given percentage of requests.
+type LatencyDistribution struct {
+	Percentage int
+	Latency    float64
+}
+
+// Report aggregates every result produced by a Work run and exposes the
+// fields consumed by the templates in print.go.
+type Report struct {
+	Total   time.Duration
+	Slowest float64
+	Fastest float64
+	Average float64
+	Rps     float64
+
+	AvgConn, ConnMax, ConnMin    float64
+	AvgDNS, DnsMax, DnsMin       float64
+	AvgReq, ReqMax, ReqMin       float64
+	AvgDelay, DelayMax, DelayMin float64
+	AvgRes, ResMax, ResMin       float64
+
+	Lats        []float64
+	ConnLats    []float64
+	DnsLats     []float64
+	ReqLats     []float64
+	DelayLats   []float64
+	ResLats     []float64
+	Offsets     []float64
+	StatusCodes []int
+
+	SizeTotal int64
+	SizeReq   int64
+
+	Histogram           []Bucket
+	LatencyDistribution []LatencyDistribution
+	StatusCodeDist      map[int]int
+	ErrorDist           map[string]int
+
+	// RPSBucketWidth is the width, in seconds, of each RPSSeries /
+	// StatusCodeSeries bucket. RPS-over-time plotting needs this
+	// alongside the raw counts to know what each point spans.
+	RPSBucketWidth float64
+	// RPSSeries is requests-per-second, bucketed by completion time
+	// (derived from Offsets+Lats) into RPSBucketWidth windows. It lets
+	// --plot draw an RPS-over-time chart without re-deriving it from
+	// the CSV after the fact.
+	RPSSeries []float64
+	// StatusCodeSeries is, per status code, the count of responses
+	// completed in each RPSBucketWidth window, for the --plot
+	// per-status-code chart, which draws one line per code; the series
+	// are not stacked or filled.
+	StatusCodeSeries map[int][]float64
+
+	// ByStep holds per-step latency/status breakdowns for a --scenario
+	// run. It is nil for a plain single-URL run, so the default
+	// template's output is unchanged unless scenarios are in play.
+	ByStep []*StepReport
+
+	output string
+	w      io.Writer
+	n      int
+
+	results chan *result
+
+	// stream is the incremental counterpart of the fields above. It is
+	// always populated (cheaply) so that a --live run has something to
+	// render without waiting for finalize. Its spec is normalized (a
+	// zero BucketSpec becomes a fixed default for live rendering), so
+	// finalize must not use it to decide between fixed and adaptive
+	// bucketing -- it consults histogramSpec, the caller's original,
+	// un-normalized value, for that instead.
+	stream        *StreamStats
+	histogramSpec BucketSpec
+
+	onResult func(Result)
+
+	// done is closed once collect's range over results returns, so
+	// finalize can block until every buffered result has actually been
+	// folded into the fields above instead of racing collect's writes.
+	done chan struct{}
+}
+
+func newReport(w io.Writer, results chan *result, output string, n int, spec BucketSpec, onResult func(Result)) *Report {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &Report{
+		output:         output,
+		w:              w,
+		n:              n,
+		results:        results,
+		StatusCodeDist: make(map[int]int),
+		ErrorDist:      make(map[string]int),
+		stream:         NewStreamStats(spec),
+		histogramSpec:  spec,
+		onResult:       onResult,
+		done:           make(chan struct{}),
+	}
+}
+
+// collect drains the results channel as requests complete, accumulating
+// the raw samples used to compute the final summary. For "ndjson"
+// output it instead streams one JSON object per request straight to
+// r.w as they arrive, rather than waiting for the run to finish. It
+// closes r.done once results is drained, so callers can wait for
+// collect to finish before reading the accumulated fields.
+func (r *Report) collect() {
+	defer close(r.done)
+	var ndjson *json.Encoder
+	if r.output == "ndjson" {
+		ndjson = json.NewEncoder(r.w)
+	}
+	for res := range r.results {
+		r.stream.Observe(res)
+		if r.onResult != nil {
+			r.onResult(res.export())
+		}
+		if ndjson != nil {
+			ndjson.Encode(newNDJSONRecord(res))
+			continue
+		}
+		if res.err != nil {
+			r.ErrorDist[res.err.Error()]++
+			continue
+		}
+		r.Lats = append(r.Lats, res.duration.Seconds())
+		r.ConnLats = append(r.ConnLats, res.connDuration.Seconds())
+		r.DnsLats = append(r.DnsLats, res.dnsDuration.Seconds())
+		r.ReqLats = append(r.ReqLats, res.reqDuration.Seconds())
+		r.DelayLats = append(r.DelayLats, res.delayDuration.Seconds())
+		r.ResLats = append(r.ResLats, res.resDuration.Seconds())
+		r.Offsets = append(r.Offsets, res.offset.Seconds())
+		r.StatusCodes = append(r.StatusCodes, res.statusCode)
+		r.StatusCodeDist[res.statusCode]++
+		r.SizeTotal += res.contentLength
+	}
+}
+
+// wait blocks until collect has drained every buffered result. Callers
+// must close the results channel first, or this blocks forever.
+func (r *Report) wait() {
+	<-r.done
+}
+
+// finalize computes the summary statistics once every result has been
+// collected and writes the selected output format. ndjson output has
+// already been streamed line-by-line during collect, so there is
+// nothing left to render here. Callers must call wait first.
+func (r *Report) finalize() {
+	if r.output == "ndjson" {
+		return
+	}
+	if len(r.Lats) > 0 {
+		r.Total = time.Duration(maxElapsed(r.Offsets, r.Lats) * float64(time.Second))
+		r.Average = average(r.Lats)
+		r.Fastest, r.Slowest = minMax(r.Lats)
+		r.AvgConn, r.ConnMin, r.ConnMax = average(r.ConnLats), min2(r.ConnLats), max2(r.ConnLats)
+		r.AvgDNS, r.DnsMin, r.DnsMax = average(r.DnsLats), min2(r.DnsLats), max2(r.DnsLats)
+		r.AvgReq, r.ReqMin, r.ReqMax = average(r.ReqLats), min2(r.ReqLats), max2(r.ReqLats)
+		r.AvgDelay, r.DelayMin, r.DelayMax = average(r.DelayLats), min2(r.DelayLats), max2(r.DelayLats)
+		r.AvgRes, r.ResMin, r.ResMax = average(r.ResLats), min2(r.ResLats), max2(r.ResLats)
+		if r.n > 0 {
+			r.SizeReq = r.SizeTotal / int64(len(r.Lats))
+		}
+		r.Rps = float64(len(r.Lats)) / r.Total.Seconds()
+		if r.histogramSpec.Kind != "" || len(r.histogramSpec.Bounds) > 0 {
+			r.Histogram = histogramFromSpec(r.Lats, r.stream.spec)
+		} else {
+			r.Histogram = makeHistogram(r.Lats, r.Slowest, r.Fastest, 10)
+		}
+		r.LatencyDistribution = makeLatencyDistribution(r.Lats)
+		r.RPSBucketWidth = 0.1
+		r.RPSSeries, r.StatusCodeSeries = makeTimeSeries(r.Offsets, r.Lats, r.StatusCodes, r.RPSBucketWidth)
+	}
+
+	tmpl := newTemplate(r.output)
+	tmpl.Execute(r.w, r)
+}
+
+func sum(vs []float64) float64 {
+	var s float64
+	for _, v := range vs {
+		s += v
+	}
+	return s
+}
+
+func average(vs []float64) float64 {
+	if len(vs) == 0 {
+		return 0
+	}
+	return sum(vs) / float64(len(vs))
+}
+
+func minMax(vs []float64) (float64, float64) {
+	return min2(vs), max2(vs)
+}
+
+// maxElapsed returns the run's wall-clock duration: the latest
+// completion time (start offset + latency) across every request, not
+// the sum of every request's offset.
+func maxElapsed(offsets, lats []float64) float64 {
+	var max float64
+	for i, o := range offsets {
+		if t := o + lats[i]; t > max {
+			max = t
+		}
+	}
+	return max
+}
+
+func min2(vs []float64) float64 {
+	if len(vs) == 0 {
+		return 0
+	}
+	m := vs[0]
+	for _, v := range vs {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func max2(vs []float64) float64 {
+	if len(vs) == 0 {
+		return 0
+	}
+	m := vs[0]
+	for _, v := range vs {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+func makeHistogram(lats []float64, slowest, fastest float64, buckets int) []Bucket {
+	bs := make([]Bucket, buckets+1)
+	bw := (slowest - fastest) / float64(buckets)
+	for i := 0; i < len(bs); i++ {
+		bs[i].Mark = fastest + bw*float64(i)
+	}
+	for i := range bs {
+		if i+1 < len(bs) {
+			bs[i].UpperBound = bs[i+1].Mark
+		} else {
+			bs[i].UpperBound = bs[i].Mark
+		}
+	}
+	if bw == 0 {
+		// Every latency is identical (including the common -n 1
+		// case), so there's only one bucket to put them in.
+		bs[0].Count = len(lats)
+		return bs
+	}
+	for _, l := range lats {
+		idx := int((l - fastest) / bw)
+		if idx >= len(bs) {
+			idx = len(bs) - 1
+		}
+		if idx < 0 {
+			idx = 0
+		}
+		bs[idx].Count++
+	}
+	return bs
+}
+
+func makeLatencyDistribution(lats []float64) []LatencyDistribution {
+	sorted := append([]float64(nil), lats...)
+	sort.Float64s(sorted)
+	pctls := []int{10, 25, 50, 75, 90, 95, 99}
+	dist := make([]LatencyDistribution, 0, len(pctls))
+	for _, p := range pctls {
+		idx := (p * len(sorted) / 100)
+		if idx >= len(sorted) {
+			idx = len(sorted) - 1
+		}
+		if idx < 0 {
+			continue
+		}
+		dist = append(dist, LatencyDistribution{Percentage: p, Latency: sorted[idx]})
+	}
+	return dist
+}
+
+// makeTimeSeries buckets request completions (offset+latency) into
+// width-second windows, returning requests-per-second overall and,
+// per status code, the raw count per window.
+func makeTimeSeries(offsets, lats []float64, codes []int, width float64) ([]float64, map[int][]float64) {
+	if len(offsets) == 0 {
+		return nil, nil
+	}
+	maxT := 0.0
+	for i := range offsets {
+		if t := offsets[i] + lats[i]; t > maxT {
+			maxT = t
+		}
+	}
+	n := int(maxT/width) + 1
+	rps := make([]float64, n)
+	byStatus := make(map[int][]float64)
+	for i := range offsets {
+		idx := int((offsets[i] + lats[i]) / width)
+		if idx >= n {
+			idx = n - 1
+		}
+		rps[idx]++
+		if i < len(codes) {
+			series, ok := byStatus[codes[i]]
+			if !ok {
+				series = make([]float64, n)
+				byStatus[codes[i]] = series
+			}
+			series[idx]++
+		}
+	}
+	for i := range rps {
+		rps[i] /= width
+	}
+	return rps, byStatus
+}
@@ -0,0 +1,323 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Work is the main struct that drives a benchmark run. It holds all the
+// configuration needed to repeatedly issue a request and collects the
+// timing of every request into a Report once the run completes.
+type Work struct {
+	// Request is the request to be made.
+	Request *http.Request
+
+	RequestBody []byte
+
+	// N is the total number of requests to make.
+	N int
+
+	// C is the concurrency level, the number of concurrent workers to run.
+	C int
+
+	// QPS is the rate limit in queries per second.
+	QPS float64
+
+	// Output represents the output type. If "csv" is provided, the
+	// output will be dumped as a csv stream. See newTemplate for other
+	// supported values.
+	Output string
+
+	// Transport is the http.RoundTripper used for every request, so
+	// proxying, TLS and HTTP/2 settings are configured in one place.
+	Transport http.RoundTripper
+
+	// Writer is where results will be written. If nil, os.Stdout is used.
+	Writer io.Writer
+
+	// DisableRedirects is an option to prevent the following of HTTP redirects.
+	DisableRedirects bool
+
+	// Timeout is the per-request timeout, in seconds. Zero means no timeout.
+	Timeout int
+
+	// TLSConfig is the tls configuration to use for HTTPS requests.
+	TLSConfig *tls.Config
+
+	// Live, when set, renders a live-updating terminal view of running
+	// totals (RPS, error rate, status codes, histogram, quantiles) as
+	// the benchmark progresses, instead of only printing at the end.
+	Live bool
+
+	// LiveInterval is how often the live view redraws. Zero uses a
+	// 200ms default.
+	LiveInterval time.Duration
+
+	// HistogramSpec configures the bucketing of the response time
+	// histogram, live or final. The zero value keeps the historical
+	// behavior of 10 linear buckets spanning fastest..slowest.
+	HistogramSpec BucketSpec
+
+	// PushGatewayURL, if set, pushes the run's metrics to a Prometheus
+	// Pushgateway at this address once the run finishes, in addition to
+	// whatever Output format was requested.
+	PushGatewayURL string
+
+	// PushGatewayJob names the job under which metrics are grouped on
+	// the Pushgateway. Defaults to "hey" if empty.
+	PushGatewayJob string
+
+	// PlotDir, if set, writes latency histogram, percentile, RPS-over-time
+	// and status-code charts to this directory once the run finishes.
+	PlotDir string
+
+	// PlotSVG also writes an SVG alongside each PNG in PlotDir.
+	PlotSVG bool
+
+	// OnResult, if set, is called with every completed request as it
+	// finishes, in addition to normal report collection. The
+	// coordinator package uses this to stream a worker's results back
+	// to the coordinator without needing access to Report internals.
+	OnResult func(Result)
+
+	// StartAt overrides the instant offsets are measured from. The
+	// coordinator package sets this to a time shared by every worker so
+	// offsets are comparable once merged. Zero means "now".
+	StartAt time.Time
+
+	initOnce sync.Once
+	results  chan *result
+	stopCh   chan struct{}
+	start    time.Time
+	qps      chan time.Time
+
+	report *Report
+}
+
+type result struct {
+	err           error
+	statusCode    int
+	offset        time.Duration
+	duration      time.Duration
+	connDuration  time.Duration
+	dnsDuration   time.Duration
+	reqDuration   time.Duration
+	resDuration   time.Duration
+	delayDuration time.Duration
+	contentLength int64
+}
+
+// Result is the exported, stage-labeled view of one completed request,
+// in seconds, for consumers outside this package (currently just the
+// coordinator's worker-to-coordinator result streaming).
+type Result struct {
+	Status        int
+	Offset        float64
+	Duration      float64
+	ConnDuration  float64
+	DNSDuration   float64
+	ReqDuration   float64
+	ResDuration   float64
+	DelayDuration float64
+	ContentLength int64
+	Err           error
+}
+
+func (res *result) export() Result {
+	return Result{
+		Status:        res.statusCode,
+		Offset:        res.offset.Seconds(),
+		Duration:      res.duration.Seconds(),
+		ConnDuration:  res.connDuration.Seconds(),
+		DNSDuration:   res.dnsDuration.Seconds(),
+		ReqDuration:   res.reqDuration.Seconds(),
+		ResDuration:   res.resDuration.Seconds(),
+		DelayDuration: res.delayDuration.Seconds(),
+		ContentLength: res.contentLength,
+		Err:           res.err,
+	}
+}
+
+func (b *Work) init() {
+	b.initOnce.Do(func() {
+		b.results = make(chan *result, min(b.C*1000, 100000))
+		b.stopCh = make(chan struct{}, b.C)
+	})
+}
+
+// Run makes all the requests, prints the summary. It blocks until
+// all work is done.
+func (b *Work) Run() {
+	b.init()
+	b.start = b.StartAt
+	if b.start.IsZero() {
+		b.start = time.Now()
+	}
+	b.report = newReport(b.Writer, b.results, b.Output, b.N, b.HistogramSpec, b.OnResult)
+	go b.report.collect()
+
+	var live *liveRenderer
+	if b.Live {
+		live = newLiveRenderer(b.Writer, b.report, b.LiveInterval, b.start)
+		go live.run()
+	}
+
+	var ticker *time.Ticker
+	if b.QPS > 0 {
+		b.qps = make(chan time.Time)
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / b.QPS))
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			for {
+				select {
+				case t := <-ticker.C:
+					b.qps <- t
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+
+	b.runWorkers()
+	close(b.results)
+	if ticker != nil {
+		ticker.Stop()
+	}
+	if live != nil {
+		live.Stop()
+	}
+	b.report.wait()
+	b.report.finalize()
+
+	if b.PushGatewayURL != "" {
+		job := b.PushGatewayJob
+		if job == "" {
+			job = "hey"
+		}
+		w := b.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		if err := PushToGateway(b.PushGatewayURL, job, b.report); err != nil {
+			fmt.Fprintf(w, "requester: failed to push to gateway: %v\n", err)
+		}
+	}
+
+	if b.PlotDir != "" {
+		w := b.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		if err := SavePlots(b.PlotDir, b.report, b.PlotSVG); err != nil {
+			fmt.Fprintf(w, "requester: failed to save plots: %v\n", err)
+		}
+	}
+}
+
+// Stop signals the workers to stop making further requests.
+func (b *Work) Stop() {
+	for i := 0; i < b.C; i++ {
+		b.stopCh <- struct{}{}
+	}
+}
+
+func (b *Work) runWorkers() {
+	var wg sync.WaitGroup
+	wg.Add(b.C)
+
+	client := &http.Client{
+		Transport: b.Transport,
+		Timeout:   time.Duration(b.Timeout) * time.Second,
+	}
+	if b.DisableRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	n := b.N / b.C
+	for i := 0; i < b.C; i++ {
+		go func() {
+			defer wg.Done()
+			b.runWorker(client, n)
+		}()
+	}
+	wg.Wait()
+}
+
+func (b *Work) runWorker(client *http.Client, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-b.stopCh:
+			return
+		default:
+			if b.qps != nil {
+				<-b.qps
+			}
+			b.makeRequest(client)
+		}
+	}
+}
+
+func (b *Work) makeRequest(c *http.Client) {
+	s := time.Now()
+	req := cloneRequest(b.Request, b.RequestBody)
+	res, _ := execRequest(c, req, false)
+
+	b.results <- &result{
+		offset:        s.Sub(b.start),
+		statusCode:    res.Status,
+		duration:      time.Duration(res.Duration * float64(time.Second)),
+		err:           res.Err,
+		contentLength: res.ContentLength,
+		connDuration:  time.Duration(res.ConnDuration * float64(time.Second)),
+		dnsDuration:   time.Duration(res.DNSDuration * float64(time.Second)),
+		reqDuration:   time.Duration(res.ReqDuration * float64(time.Second)),
+		resDuration:   time.Duration(res.ResDuration * float64(time.Second)),
+		delayDuration: time.Duration(res.DelayDuration * float64(time.Second)),
+	}
+}
+
+func cloneRequest(r *http.Request, body []byte) *http.Request {
+	req := new(http.Request)
+	*req = *r
+	req.Header = make(http.Header, len(r.Header))
+	for k, v := range r.Header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	if len(body) > 0 {
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+	}
+	return req
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
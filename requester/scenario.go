@@ -0,0 +1,301 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScenarioStep is one named request in a scenario file. URL, Headers
+// and Body are Go text/template source, expanded per-virtual-user with
+// randInt/uuid/randChoiceFromFile and any values captured from earlier
+// steps' responses.
+type ScenarioStep struct {
+	Name      string            `yaml:"name"`
+	Weight    int               `yaml:"weight"`
+	Method    string            `yaml:"method"`
+	URL       string            `yaml:"url"`
+	Headers   map[string]string `yaml:"headers"`
+	Body      string            `yaml:"body"`
+	ThinkTime time.Duration     `yaml:"think_time"`
+	// Capture maps a variable name to a JSONPath expression run
+	// against this step's response body; later steps (and later
+	// iterations, for the same virtual user) can reference it as
+	// {{ .Vars.name }}.
+	Capture map[string]string `yaml:"capture"`
+}
+
+// Scenario is the parsed form of a --scenario file. Mode is either
+// "weighted" (each virtual user picks its next step by Step.Weight) or
+// "sequence" (each virtual user executes every step in order,
+// repeating from the top), for stateful multi-step flows like
+// login->list->get.
+type Scenario struct {
+	Mode  string         `yaml:"mode"`
+	Steps []ScenarioStep `yaml:"steps"`
+
+	totalWeight int
+}
+
+// LoadScenario reads and validates a --scenario YAML file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("requester: parsing scenario: %w", err)
+	}
+	if len(s.Steps) == 0 {
+		return nil, fmt.Errorf("requester: scenario has no steps")
+	}
+	if s.Mode == "" {
+		s.Mode = "sequence"
+	}
+	for _, step := range s.Steps {
+		s.totalWeight += step.Weight
+	}
+	return &s, nil
+}
+
+// nextStep picks vu's next step: the i'th step in order for "sequence"
+// mode, or a weight-proportional random pick for "weighted" mode.
+func (s *Scenario) nextStep(vu *vuState, i int) ScenarioStep {
+	if s.Mode == "weighted" && s.totalWeight > 0 {
+		n := vu.rng.Intn(s.totalWeight)
+		for _, step := range s.Steps {
+			if n < step.Weight {
+				return step
+			}
+			n -= step.Weight
+		}
+	}
+	return s.Steps[i%len(s.Steps)]
+}
+
+// vuState is the per-virtual-user state threaded through a scenario
+// run: captured variables from prior responses and a private RNG
+// (shared use of math/rand's global source would serialize VUs).
+type vuState struct {
+	vars map[string]string
+	rng  *rngSource
+}
+
+func newVUState() *vuState {
+	return &vuState{vars: make(map[string]string), rng: newRNGSource()}
+}
+
+func (vu *vuState) funcMap() template.FuncMap {
+	return template.FuncMap{
+		"randInt":            func(min, max int) int { return min + vu.rng.Intn(max-min+1) },
+		"uuid":               uuidV4,
+		"randChoiceFromFile": randChoiceFromFile,
+	}
+}
+
+func (vu *vuState) expand(text string) (string, error) {
+	tmpl, err := template.New("scenario").Funcs(vu.funcMap()).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	data := struct{ Vars map[string]string }{vu.vars}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ScenarioWork drives a --scenario file across VUs virtual users, the
+// scenario analogue of Work for a single fixed request.
+type ScenarioWork struct {
+	Scenario  *Scenario
+	N         int
+	VUs       int
+	Timeout   int
+	Transport http.RoundTripper
+	Writer    io.Writer
+	Output    string
+}
+
+// Run executes the scenario and returns the merged, per-step-annotated
+// report.
+func (s *ScenarioWork) Run() *Report {
+	client := &http.Client{
+		Transport: s.Transport,
+		Timeout:   time.Duration(s.Timeout) * time.Second,
+	}
+
+	results := make(chan StepResult, min(s.VUs*1000, 100000))
+	var wg sync.WaitGroup
+	start := time.Now()
+	perVU := s.N / s.VUs
+
+	wg.Add(s.VUs)
+	for i := 0; i < s.VUs; i++ {
+		go func() {
+			defer wg.Done()
+			vu := newVUState()
+			for j := 0; j < perVU; j++ {
+				step := s.Scenario.nextStep(vu, j)
+				res := s.runStep(client, vu, step, start)
+				results <- StepResult{Step: step.Name, Result: res}
+				if step.ThinkTime > 0 {
+					time.Sleep(step.ThinkTime)
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var all []StepResult
+	for r := range results {
+		all = append(all, r)
+	}
+	return NewScenarioReport(s.Writer, s.Output, all)
+}
+
+func (s *ScenarioWork) runStep(client *http.Client, vu *vuState, step ScenarioStep, start time.Time) Result {
+	reqStart := time.Now()
+
+	url, err := vu.expand(step.URL)
+	if err != nil {
+		return Result{Err: err, Offset: reqStart.Sub(start).Seconds()}
+	}
+	body, err := vu.expand(step.Body)
+	if err != nil {
+		return Result{Err: err, Offset: reqStart.Sub(start).Seconds()}
+	}
+
+	method := step.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+	if err != nil {
+		return Result{Err: err, Offset: reqStart.Sub(start).Seconds()}
+	}
+	for k, v := range step.Headers {
+		hv, err := vu.expand(v)
+		if err != nil {
+			return Result{Err: err, Offset: reqStart.Sub(start).Seconds()}
+		}
+		req.Header.Set(k, hv)
+	}
+
+	res, respBody := execRequest(client, req, len(step.Capture) > 0)
+	res.Offset = reqStart.Sub(start).Seconds()
+
+	for name, path := range step.Capture {
+		if v, err := jsonpathExtract(respBody, path); err == nil {
+			vu.vars[name] = v
+		}
+	}
+	return res
+}
+
+// rngSource is a tiny, non-cryptographic RNG seeded from crypto/rand so
+// concurrent virtual users don't share (and contend on) math/rand's
+// global source.
+type rngSource struct {
+	state uint64
+}
+
+func newRNGSource() *rngSource {
+	var seed uint64
+	if n, err := rand.Int(rand.Reader, big.NewInt(1<<62)); err == nil {
+		seed = n.Uint64()
+	}
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	return &rngSource{state: seed}
+}
+
+// Intn returns a value in [0, n). It uses a simple xorshift64 step,
+// which is more than adequate for picking a weighted step or a random
+// int range -- this isn't used for anything security-sensitive.
+func (r *rngSource) Intn(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	r.state ^= r.state << 13
+	r.state ^= r.state >> 7
+	r.state ^= r.state << 17
+	return int(r.state % uint64(n))
+}
+
+func uuidV4() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+var (
+	choiceFilesMu sync.Mutex
+	choiceFiles   = make(map[string][]string)
+)
+
+// randChoiceFromFile returns a random line from path, caching the
+// file's lines after the first read so a scenario doesn't re-read a
+// names/emails/IDs fixture file on every single request.
+func randChoiceFromFile(path string) (string, error) {
+	choiceFilesMu.Lock()
+	lines, ok := choiceFiles[path]
+	choiceFilesMu.Unlock()
+	if !ok {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		choiceFilesMu.Lock()
+		choiceFiles[path] = lines
+		choiceFilesMu.Unlock()
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("requester: %s has no choices", path)
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(lines))))
+	if err != nil {
+		return "", err
+	}
+	return lines[n.Int64()], nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"io"
+)
+
+// StepResult is one completed request from a --scenario run, labeled
+// with which step of the scenario produced it.
+type StepResult struct {
+	Step string
+	Result
+}
+
+// StepReport is the per-step breakdown of a scenario run, computed
+// alongside (and included in) the aggregate Report.
+type StepReport struct {
+	Name           string
+	Count          int
+	Average        float64
+	Fastest        float64
+	Slowest        float64
+	StatusCodeDist map[int]int
+	ErrorDist      map[string]int
+}
+
+// NewScenarioReport builds an aggregate Report from a --scenario run's
+// results, the same way NewMergedReport does for the coordinator, and
+// additionally fills in ByStep so templates can render a per-step
+// breakdown via `{{ range .ByStep }}` without losing the backward
+// compatible aggregate view.
+func NewScenarioReport(w io.Writer, output string, results []StepResult) *Report {
+	plain := make([]Result, len(results))
+	byStep := make(map[string][]Result)
+	for i, sr := range results {
+		plain[i] = sr.Result
+		byStep[sr.Step] = append(byStep[sr.Step], sr.Result)
+	}
+
+	r := newReport(w, nil, output, len(results), BucketSpec{}, nil)
+	accumulate(r, plain)
+
+	names := make([]string, 0, len(byStep))
+	seen := make(map[string]bool, len(byStep))
+	for _, sr := range results {
+		if !seen[sr.Step] {
+			seen[sr.Step] = true
+			names = append(names, sr.Step)
+		}
+	}
+	for _, name := range names {
+		r.ByStep = append(r.ByStep, stepReportFor(name, byStep[name]))
+	}
+
+	r.finalize()
+	return r
+}
+
+func stepReportFor(name string, results []Result) *StepReport {
+	sr := &StepReport{
+		Name:           name,
+		StatusCodeDist: make(map[int]int),
+		ErrorDist:      make(map[string]int),
+	}
+	var lats []float64
+	var total float64
+	for _, res := range results {
+		if res.Err != nil {
+			sr.ErrorDist[res.Err.Error()]++
+			continue
+		}
+		sr.Count++
+		sr.StatusCodeDist[res.Status]++
+		lats = append(lats, res.Duration)
+		total += res.Duration
+	}
+	if sr.Count > 0 {
+		sr.Average = total / float64(sr.Count)
+		sr.Fastest, sr.Slowest = minMax(lats)
+	}
+	return sr
+}
@@ -0,0 +1,414 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requester
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// targetedQuantile is a (quantile, epsilon) pair that the streaming
+// estimator keeps bounded-rank-error guarantees for, e.g. {0.99, 0.001}
+// means "report p99 with rank error no worse than 0.1%".
+type targetedQuantile struct {
+	quantile, epsilon float64
+}
+
+var defaultTargets = []targetedQuantile{
+	{0.50, 0.01},
+	{0.90, 0.005},
+	{0.95, 0.002},
+	{0.99, 0.001},
+}
+
+// sample is one (value, g, delta) tuple of the Cormode/Korn/Muthukrishnan
+// targeted-quantiles summary: g is the number of items represented by this
+// tuple since the previous one, and delta bounds how much the tuple's true
+// rank can differ from its minimum possible rank.
+type sample struct {
+	value    float64
+	g, delta int
+}
+
+// streamQuantiles is a streaming quantile estimator with bounded memory,
+// following the algorithm used by prometheus/client_golang's summary type
+// (itself based on github.com/beorn7/perks). It lets StreamStats report
+// p50/p90/p95/p99 without retaining every observed latency.
+type streamQuantiles struct {
+	targets []targetedQuantile
+	samples []sample
+	n       int
+
+	// buffered observations not yet merged into samples; merging in
+	// batches amortizes the O(n) insert cost.
+	buf []float64
+}
+
+func newStreamQuantiles() *streamQuantiles {
+	return &streamQuantiles{targets: defaultTargets}
+}
+
+const streamFlushThreshold = 500
+
+func (s *streamQuantiles) Insert(v float64) {
+	s.buf = append(s.buf, v)
+	if len(s.buf) >= streamFlushThreshold {
+		s.flush()
+	}
+}
+
+func (s *streamQuantiles) flush() {
+	if len(s.buf) == 0 {
+		return
+	}
+	sort.Float64s(s.buf)
+	for _, v := range s.buf {
+		s.insert(v)
+	}
+	s.buf = s.buf[:0]
+	s.compress()
+}
+
+func (s *streamQuantiles) insert(v float64) {
+	i := sort.Search(len(s.samples), func(i int) bool { return s.samples[i].value >= v })
+	var delta int
+	if i == 0 || i == len(s.samples) {
+		delta = 0
+	} else {
+		delta = s.invariant(i) - 1
+		if delta < 0 {
+			delta = 0
+		}
+	}
+	newSample := sample{value: v, g: 1, delta: delta}
+	s.samples = append(s.samples, sample{})
+	copy(s.samples[i+1:], s.samples[i:])
+	s.samples[i] = newSample
+	s.n++
+}
+
+// invariant returns the maximum allowed (g+delta) at the rank of
+// sample position i, the bound used both to seed a new sample's delta
+// and to decide what can be coalesced away during compression. It's
+// the same rank-dependent f(r,n) bound compress uses via maxError, so a
+// freshly inserted sample is seeded with exactly the slack it's allowed
+// to have at its own rank, rather than one fixed, rank-independent
+// slack for every insert.
+func (s *streamQuantiles) invariant(i int) int {
+	rank := 0
+	for _, sm := range s.samples[:i] {
+		rank += sm.g
+	}
+	return s.maxError(rank)
+}
+
+// compress coalesces adjacent samples whenever doing so still satisfies
+// every targeted quantile's rank-error bound, keeping memory bounded
+// regardless of how many requests have completed.
+func (s *streamQuantiles) compress() {
+	if len(s.samples) < 2 {
+		return
+	}
+	rank := 0
+	out := s.samples[:1]
+	for i := 1; i < len(s.samples); i++ {
+		cur := s.samples[i]
+		prev := &out[len(out)-1]
+		rank += prev.g
+		if prev.g+cur.g+cur.delta <= s.maxError(rank) {
+			prev.g += cur.g
+			continue
+		}
+		out = append(out, cur)
+	}
+	s.samples = out
+}
+
+func (s *streamQuantiles) maxError(rank int) int {
+	best := math.MaxFloat64
+	for _, t := range s.targets {
+		var f float64
+		if float64(rank) <= t.quantile*float64(s.n) {
+			f = 2 * t.epsilon * float64(s.n-rank) / (1 - t.quantile)
+		} else {
+			f = 2 * t.epsilon * float64(rank) / t.quantile
+		}
+		if f < best {
+			best = f
+		}
+	}
+	return int(best)
+}
+
+// Query returns the estimated value at the given quantile (0..1).
+func (s *streamQuantiles) Query(q float64) float64 {
+	s.flush()
+	if len(s.samples) == 0 {
+		return 0
+	}
+	target := q * float64(s.n)
+	rank := 0
+	for i, sm := range s.samples {
+		rank += sm.g
+		if float64(rank)+float64(sm.delta) > target+s.maxErrorF(target) {
+			return s.samples[i].value
+		}
+	}
+	return s.samples[len(s.samples)-1].value
+}
+
+func (s *streamQuantiles) maxErrorF(rank float64) float64 {
+	return float64(s.maxError(int(rank))) / 2
+}
+
+// BucketSpec configures how StreamStats lays out its live histogram.
+// Exactly one of the strategies below is used, chosen by Kind.
+type BucketSpec struct {
+	Kind string // "linear", "exponential", or "bounds"
+
+	// Linear: Count buckets of width Width starting at Min.
+	Min, Width float64
+
+	// Exponential: Count buckets starting at Min, each Factor times the last.
+	Factor float64
+
+	Count int
+
+	// Bounds: explicit, user-supplied upper bounds (overrides Kind).
+	Bounds []float64
+}
+
+// ParseBucketSpec parses the -bucket flag's value into a BucketSpec. An
+// empty string yields the zero BucketSpec (the historical 10-bucket
+// linear fastest..slowest default). Recognized forms:
+//
+//	linear:min:width:count
+//	exponential:min:factor:count
+//	b0,b1,b2,...                (explicit, ascending upper bounds)
+func ParseBucketSpec(s string) (BucketSpec, error) {
+	if s == "" {
+		return BucketSpec{}, nil
+	}
+	parts := strings.Split(s, ":")
+	switch parts[0] {
+	case "linear", "exponential":
+		if len(parts) != 4 {
+			return BucketSpec{}, fmt.Errorf("requester: %s bucket spec wants %s:min:width-or-factor:count, got %q", parts[0], parts[0], s)
+		}
+		min, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return BucketSpec{}, fmt.Errorf("requester: invalid bucket min %q: %w", parts[1], err)
+		}
+		step, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return BucketSpec{}, fmt.Errorf("requester: invalid bucket width/factor %q: %w", parts[2], err)
+		}
+		count, err := strconv.Atoi(parts[3])
+		if err != nil {
+			return BucketSpec{}, fmt.Errorf("requester: invalid bucket count %q: %w", parts[3], err)
+		}
+		spec := BucketSpec{Kind: parts[0], Min: min, Count: count}
+		if parts[0] == "exponential" {
+			spec.Factor = step
+		} else {
+			spec.Width = step
+		}
+		return spec, nil
+	default:
+		fields := strings.Split(s, ",")
+		bounds := make([]float64, len(fields))
+		for i, f := range fields {
+			b, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+			if err != nil {
+				return BucketSpec{}, fmt.Errorf("requester: invalid bucket bound %q: %w", f, err)
+			}
+			bounds[i] = b
+		}
+		return BucketSpec{Bounds: bounds}, nil
+	}
+}
+
+func (b BucketSpec) bounds() []float64 {
+	if len(b.Bounds) > 0 {
+		return b.Bounds
+	}
+	bounds := make([]float64, b.Count)
+	switch b.Kind {
+	case "exponential":
+		v := b.Min
+		for i := range bounds {
+			bounds[i] = v
+			v *= b.Factor
+		}
+	default: // "linear"
+		for i := range bounds {
+			bounds[i] = b.Min + b.Width*float64(i)
+		}
+	}
+	return bounds
+}
+
+// histogramFromSpec buckets a completed set of latencies according to
+// spec instead of the default 10-bucket linear split between fastest
+// and slowest, so callers can pin bucket boundaries across runs (e.g.
+// to compare two benchmarks) or use exponential buckets for long tails.
+func histogramFromSpec(lats []float64, spec BucketSpec) []Bucket {
+	bounds := spec.bounds()
+	buckets := make([]Bucket, len(bounds)+1)
+	for i, b := range bounds {
+		buckets[i].Mark = b
+	}
+	if len(lats) > 0 {
+		buckets[len(buckets)-1].Mark = max2(lats)
+	}
+	for i := range buckets {
+		buckets[i].UpperBound = buckets[i].Mark
+	}
+	for _, l := range lats {
+		idx := sort.SearchFloat64s(bounds, l)
+		buckets[idx].Count++
+	}
+	return buckets
+}
+
+// StreamStats computes summary statistics incrementally as requests
+// complete, so a long or unbounded run never needs to retain every
+// latency sample in memory. It backs --live rendering. The final
+// Report's histogram and latency distribution are not read from it:
+// finalize computes them from the Report's retained latency slices.
+type StreamStats struct {
+	mu sync.Mutex
+
+	spec   BucketSpec
+	bounds []float64
+	counts []int
+
+	count      int
+	errorCount int
+	totalLat   float64
+	statusDist map[int]int
+	errorDist  map[string]int
+
+	quantiles *streamQuantiles
+
+	startOffset float64
+	lastOffset  float64
+}
+
+// NewStreamStats creates a StreamStats using spec to lay out the live
+// histogram. A zero BucketSpec falls back to 10 linear buckets between
+// 0 and 1 second, matching the default static histogram's bucket count.
+func NewStreamStats(spec BucketSpec) *StreamStats {
+	if spec.Count == 0 && len(spec.Bounds) == 0 {
+		spec = BucketSpec{Kind: "linear", Min: 0, Width: 0.1, Count: 10}
+	}
+	bounds := spec.bounds()
+	return &StreamStats{
+		spec:       spec,
+		bounds:     bounds,
+		counts:     make([]int, len(bounds)+1),
+		statusDist: make(map[int]int),
+		errorDist:  make(map[string]int),
+		quantiles:  newStreamQuantiles(),
+	}
+}
+
+// Observe folds one completed request's result into the running
+// statistics. It is safe to call from the goroutine draining Report's
+// results channel.
+func (s *StreamStats) Observe(res *result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastOffset = res.offset.Seconds()
+	if res.err != nil {
+		s.errorCount++
+		s.errorDist[res.err.Error()]++
+		return
+	}
+	lat := res.duration.Seconds()
+	s.count++
+	s.totalLat += lat
+	s.statusDist[res.statusCode]++
+	s.quantiles.Insert(lat)
+
+	idx := sort.SearchFloat64s(s.bounds, lat)
+	s.counts[idx]++
+}
+
+// Snapshot is a point-in-time read of the running statistics, cheap
+// enough to take every tick of the live renderer.
+type Snapshot struct {
+	Count              int
+	ErrorCount         int
+	Average            float64
+	Rps                float64
+	Histogram          []Bucket
+	P50, P90, P95, P99 float64
+	StatusCodeDist     map[int]int
+	ErrorDist          map[string]int
+}
+
+// Snapshot returns the current statistics. elapsed is the wall-clock
+// time since the run started, used to compute the running RPS.
+func (s *StreamStats) Snapshot(elapsed float64) Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hist := make([]Bucket, len(s.counts))
+	for i, c := range s.counts {
+		mark := math.Inf(1)
+		if i < len(s.bounds) {
+			mark = s.bounds[i]
+		}
+		hist[i] = Bucket{Mark: mark, UpperBound: mark, Count: c}
+	}
+
+	avg := 0.0
+	if s.count > 0 {
+		avg = s.totalLat / float64(s.count)
+	}
+	rps := 0.0
+	if elapsed > 0 {
+		rps = float64(s.count) / elapsed
+	}
+
+	statusDist := make(map[int]int, len(s.statusDist))
+	for k, v := range s.statusDist {
+		statusDist[k] = v
+	}
+	errorDist := make(map[string]int, len(s.errorDist))
+	for k, v := range s.errorDist {
+		errorDist[k] = v
+	}
+
+	return Snapshot{
+		Count:          s.count,
+		ErrorCount:     s.errorCount,
+		Average:        avg,
+		Rps:            rps,
+		Histogram:      hist,
+		P50:            s.quantiles.Query(0.50),
+		P90:            s.quantiles.Query(0.90),
+		P95:            s.quantiles.Query(0.95),
+		P99:            s.quantiles.Query(0.99),
+		StatusCodeDist: statusDist,
+		ErrorDist:      errorDist,
+	}
+}